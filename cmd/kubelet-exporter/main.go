@@ -6,11 +6,16 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"time"
 
 	"github.com/cofyc/kubelet-exporter/pkg/collectors"
+	"github.com/cofyc/kubelet-exporter/pkg/kubeinformer"
+	"github.com/cofyc/kubelet-exporter/pkg/kubeletclient"
+	"github.com/cofyc/kubelet-exporter/pkg/statscache"
 	"github.com/golang/glog"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics/legacyregistry"
 )
 
 const (
@@ -18,13 +23,13 @@ const (
 	healthzPath = "/healthz"
 )
 
-func metricsServer(registry prometheus.Gatherer, port int) {
+func metricsServer(port int) {
 	// Address to listen on for web interface and telemetry
 	listenAddress := fmt.Sprintf(":%d", port)
 
 	glog.Infof("Starting metrics server: %s", listenAddress)
 	// Add metricsPath
-	http.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	http.Handle(metricsPath, legacyregistry.Handler())
 	// Add healthzPath
 	http.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -49,17 +54,40 @@ func metricsServer(registry prometheus.Gatherer, port int) {
 }
 
 var (
-	optHelp           bool
-	optPort           int
-	optKubeletAddress string
+	optHelp                      bool
+	optPort                      int
+	optKubeletAddress            string
+	optKubeletScheme             string
+	optKubeletPort               int
+	optKubeletCAFile             string
+	optKubeletClientCert         string
+	optKubeletClientKey          string
+	optKubeletTokenFile          string
+	optKubeletInsecureSkipVerify bool
+	optEnableKubeAPI             bool
+	optScrapeInterval            time.Duration
 )
 
 func init() {
 	flag.BoolVar(&optHelp, "help", false, "print help info and exit")
 	flag.IntVar(&optPort, "port", 9859, "port to expose metrics on")
-	flag.StringVar(&optKubeletAddress, "kubelet-address", "http://localhost:10255", "address of kubelet")
+	flag.StringVar(&optKubeletAddress, "kubelet-address", "http://localhost:10255", "address of kubelet; ignored if NODE_NAME is set, in which case the node's address is discovered via the Kubernetes API and combined with --kubelet-scheme and --kubelet-port")
+	flag.StringVar(&optKubeletScheme, "kubelet-scheme", "https", "scheme used to reach the kubelet when its address is discovered from NODE_NAME; defaults to https because the default --kubelet-port (10250) is the kubelet's secure port and rejects plain HTTP")
+	flag.IntVar(&optKubeletPort, "kubelet-port", 10250, "port used to reach the kubelet when its address is discovered from NODE_NAME; 10250 is the secure port, 10255 the legacy read-only port")
+	flag.StringVar(&optKubeletCAFile, "kubelet-ca-file", "", "path to the CA bundle used to verify the kubelet's serving certificate, for --kubelet-scheme=https")
+	flag.StringVar(&optKubeletClientCert, "kubelet-client-cert", "", "path to a client certificate presented to the kubelet")
+	flag.StringVar(&optKubeletClientKey, "kubelet-client-key", "", "path to the private key for --kubelet-client-cert")
+	flag.StringVar(&optKubeletTokenFile, "kubelet-token-file", "", "path to a bearer token file sent as Authorization: Bearer <token> on every kubelet request; reloaded periodically")
+	flag.BoolVar(&optKubeletInsecureSkipVerify, "kubelet-insecure-skip-verify", false, "skip verification of the kubelet's serving certificate")
+	flag.BoolVar(&optEnableKubeAPI, "enable-kube-api", false, "emit kubelet_volume_stats_pvc_info, labelled with PVC storage class, bound PV, node, and phase, by watching the Kubernetes API via an in-cluster client")
+	flag.DurationVar(&optScrapeInterval, "scrape-interval", 15*time.Second, "interval at which the kubelet's /stats/summary endpoint is polled in the background; collectors serve Prometheus scrapes from the resulting cache")
 }
 
+// nodeNameEnvVar is the downward API environment variable a DaemonSet uses
+// to tell the exporter which node it is running on, so it can discover that
+// node's address and scrape its kubelet directly.
+const nodeNameEnvVar = "NODE_NAME"
+
 func main() {
 	// We log to stderr because glog will default to logging to a file.
 	flag.Set("logtostderr", "true")
@@ -70,12 +98,50 @@ func main() {
 		return
 	}
 
-	registry := prometheus.NewRegistry()
-	u, err := url.Parse(optKubeletAddress)
+	kubeletAddress := optKubeletAddress
+	if nodeName := os.Getenv(nodeNameEnvVar); nodeName != "" {
+		nodeAddress, err := kubeletclient.DiscoverNodeAddress(nodeName)
+		if err != nil {
+			log.Fatalf("failed to discover address of node %s: %v", nodeName, err)
+		}
+		kubeletAddress = fmt.Sprintf("%s://%s:%d", optKubeletScheme, nodeAddress, optKubeletPort)
+	}
+
+	u, err := url.Parse(kubeletAddress)
 	if err != nil {
 		log.Fatal(err)
 	}
 	u.Path = "stats/summary"
-	registry.MustRegister(collectors.NewVolumeStatsCollector(u.String()))
-	metricsServer(registry, optPort)
+
+	client, err := kubeletclient.NewClient(kubeletclient.Config{
+		CAFile:             optKubeletCAFile,
+		ClientCertFile:     optKubeletClientCert,
+		ClientKeyFile:      optKubeletClientKey,
+		TokenFile:          optKubeletTokenFile,
+		InsecureSkipVerify: optKubeletInsecureSkipVerify,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var pvcIndex *kubeinformer.PVCIndex
+	if optEnableKubeAPI {
+		clientset, err := kubeletclient.InClusterClientset()
+		if err != nil {
+			log.Fatal(err)
+		}
+		pvcIndex = kubeinformer.NewPVCIndex(clientset)
+		if err := pvcIndex.Run(wait.NeverStop); err != nil {
+			log.Fatal(err)
+		}
+		legacyregistry.MustRegister(collectors.NewPVCountCollector(pvcIndex))
+	}
+
+	cache := statscache.New(u.String(), client, optScrapeInterval)
+	cache.Run(wait.NeverStop)
+
+	legacyregistry.MustRegister(collectors.NewVolumeStatsCollector(cache, pvcIndex, os.Getenv(nodeNameEnvVar)))
+	legacyregistry.MustRegister(collectors.NewLogMetricsCollector(cache))
+	legacyregistry.MustRegister(collectors.NewFsStatsCollector(cache))
+	metricsServer(optPort)
 }
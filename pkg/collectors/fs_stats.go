@@ -0,0 +1,204 @@
+package collectors
+
+import (
+	"github.com/cofyc/kubelet-exporter/pkg/statscache"
+	"k8s.io/component-base/metrics"
+	"k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+const (
+	podEphemeralStorageUsedBytesKey = "kubelet_pod_ephemeral_storage_used_bytes"
+	containerRootfsUsedBytesKey     = "kubelet_container_rootfs_used_bytes"
+
+	nodeFsCapacityBytesKey  = "kubelet_node_fs_capacity_bytes"
+	nodeFsAvailableBytesKey = "kubelet_node_fs_available_bytes"
+	nodeFsUsedBytesKey      = "kubelet_node_fs_used_bytes"
+	nodeFsInodesKey         = "kubelet_node_fs_inodes"
+	nodeFsInodesFreeKey     = "kubelet_node_fs_inodes_free"
+	nodeFsInodesUsedKey     = "kubelet_node_fs_inodes_used"
+
+	nodeImageFsCapacityBytesKey  = "kubelet_node_imagefs_capacity_bytes"
+	nodeImageFsAvailableBytesKey = "kubelet_node_imagefs_available_bytes"
+	nodeImageFsUsedBytesKey      = "kubelet_node_imagefs_used_bytes"
+	nodeImageFsInodesKey         = "kubelet_node_imagefs_inodes"
+	nodeImageFsInodesFreeKey     = "kubelet_node_imagefs_inodes_free"
+	nodeImageFsInodesUsedKey     = "kubelet_node_imagefs_inodes_used"
+)
+
+var (
+	podEphemeralStorageUsedBytes = metrics.NewDesc(
+		podEphemeralStorageUsedBytesKey,
+		"Number of used bytes in the pod's ephemeral storage",
+		[]string{"namespace", "pod"}, nil,
+		metrics.ALPHA, "",
+	)
+	containerRootfsUsedBytes = metrics.NewDesc(
+		containerRootfsUsedBytesKey,
+		"Number of used bytes in the container's writable layer",
+		[]string{"namespace", "pod", "container"}, nil,
+		metrics.ALPHA, "",
+	)
+
+	nodeFsCapacityBytes = metrics.NewDesc(
+		nodeFsCapacityBytesKey,
+		"Capacity in bytes of the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeFsAvailableBytes = metrics.NewDesc(
+		nodeFsAvailableBytesKey,
+		"Number of available bytes in the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeFsUsedBytes = metrics.NewDesc(
+		nodeFsUsedBytesKey,
+		"Number of used bytes in the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeFsInodes = metrics.NewDesc(
+		nodeFsInodesKey,
+		"Maximum number of inodes in the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeFsInodesFree = metrics.NewDesc(
+		nodeFsInodesFreeKey,
+		"Number of free inodes in the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeFsInodesUsed = metrics.NewDesc(
+		nodeFsInodesUsedKey,
+		"Number of used inodes in the node's root filesystem",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+
+	nodeImageFsCapacityBytes = metrics.NewDesc(
+		nodeImageFsCapacityBytesKey,
+		"Capacity in bytes of the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeImageFsAvailableBytes = metrics.NewDesc(
+		nodeImageFsAvailableBytesKey,
+		"Number of available bytes in the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeImageFsUsedBytes = metrics.NewDesc(
+		nodeImageFsUsedBytesKey,
+		"Number of used bytes in the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeImageFsInodes = metrics.NewDesc(
+		nodeImageFsInodesKey,
+		"Maximum number of inodes in the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeImageFsInodesFree = metrics.NewDesc(
+		nodeImageFsInodesFreeKey,
+		"Number of free inodes in the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+	nodeImageFsInodesUsed = metrics.NewDesc(
+		nodeImageFsInodesUsedKey,
+		"Number of used inodes in the node's filesystem used for images and container-writable layers",
+		nil, nil,
+		metrics.ALPHA, "",
+	)
+)
+
+// fsStatsCollector collects pod ephemeral-storage, container rootfs and
+// node-level filesystem metrics from a cached kubelet stats summary.
+type fsStatsCollector struct {
+	metrics.BaseStableCollector
+
+	cache *statscache.Cache
+}
+
+// NewFsStatsCollector creates a new filesystem stats prometheus collector
+// that reads from cache rather than scraping the kubelet itself.
+func NewFsStatsCollector(cache *statscache.Cache) metrics.StableCollector {
+	return &fsStatsCollector{cache: cache}
+}
+
+// DescribeWithStability implements the metrics.StableCollector interface.
+func (collector *fsStatsCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- podEphemeralStorageUsedBytes
+	ch <- containerRootfsUsedBytes
+	ch <- nodeFsCapacityBytes
+	ch <- nodeFsAvailableBytes
+	ch <- nodeFsUsedBytes
+	ch <- nodeFsInodes
+	ch <- nodeFsInodesFree
+	ch <- nodeFsInodesUsed
+	ch <- nodeImageFsCapacityBytes
+	ch <- nodeImageFsAvailableBytes
+	ch <- nodeImageFsUsedBytes
+	ch <- nodeImageFsInodes
+	ch <- nodeImageFsInodesFree
+	ch <- nodeImageFsInodesUsed
+}
+
+// CollectWithStability implements the metrics.StableCollector interface.
+func (collector *fsStatsCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	statsSummary := collector.cache.Get()
+
+	for _, podStats := range statsSummary.Pods {
+		if podStats.EphemeralStorage != nil && podStats.EphemeralStorage.UsedBytes != nil {
+			ch <- metrics.NewLazyConstMetric(
+				podEphemeralStorageUsedBytes, metrics.GaugeValue,
+				float64(*podStats.EphemeralStorage.UsedBytes),
+				podStats.PodRef.Namespace, podStats.PodRef.Name,
+			)
+		}
+		for _, containerStats := range podStats.Containers {
+			if containerStats.Rootfs == nil || containerStats.Rootfs.UsedBytes == nil {
+				continue
+			}
+			ch <- metrics.NewLazyConstMetric(
+				containerRootfsUsedBytes, metrics.GaugeValue,
+				float64(*containerStats.Rootfs.UsedBytes),
+				podStats.PodRef.Namespace, podStats.PodRef.Name, containerStats.Name,
+			)
+		}
+	}
+
+	if fs := statsSummary.Node.Fs; fs != nil {
+		addFsGauges(ch, fs, nodeFsCapacityBytes, nodeFsAvailableBytes, nodeFsUsedBytes, nodeFsInodes, nodeFsInodesFree, nodeFsInodesUsed)
+	}
+	if statsSummary.Node.Runtime != nil {
+		if imageFs := statsSummary.Node.Runtime.ImageFs; imageFs != nil {
+			addFsGauges(ch, imageFs, nodeImageFsCapacityBytes, nodeImageFsAvailableBytes, nodeImageFsUsedBytes, nodeImageFsInodes, nodeImageFsInodesFree, nodeImageFsInodesUsed)
+		}
+	}
+}
+
+// addFsGauges emits the common set of capacity/available/used bytes and
+// inode gauges shared by the node's root filesystem and image filesystem.
+func addFsGauges(ch chan<- metrics.Metric, fs *v1alpha1.FsStats, capacityDesc, availableDesc, usedDesc, inodesDesc, inodesFreeDesc, inodesUsedDesc *metrics.Desc) {
+	if fs.CapacityBytes != nil {
+		ch <- metrics.NewLazyConstMetric(capacityDesc, metrics.GaugeValue, float64(*fs.CapacityBytes))
+	}
+	if fs.AvailableBytes != nil {
+		ch <- metrics.NewLazyConstMetric(availableDesc, metrics.GaugeValue, float64(*fs.AvailableBytes))
+	}
+	if fs.UsedBytes != nil {
+		ch <- metrics.NewLazyConstMetric(usedDesc, metrics.GaugeValue, float64(*fs.UsedBytes))
+	}
+	if fs.Inodes != nil {
+		ch <- metrics.NewLazyConstMetric(inodesDesc, metrics.GaugeValue, float64(*fs.Inodes))
+	}
+	if fs.InodesFree != nil {
+		ch <- metrics.NewLazyConstMetric(inodesFreeDesc, metrics.GaugeValue, float64(*fs.InodesFree))
+	}
+	if fs.InodesUsed != nil {
+		ch <- metrics.NewLazyConstMetric(inodesUsedDesc, metrics.GaugeValue, float64(*fs.InodesUsed))
+	}
+}
@@ -0,0 +1,57 @@
+package collectors
+
+import (
+	"github.com/cofyc/kubelet-exporter/pkg/statscache"
+	"k8s.io/component-base/metrics"
+)
+
+const (
+	containerLogFilesystemUsedBytesKey = "kubelet_container_log_filesystem_used_bytes"
+)
+
+var (
+	containerLogFilesystemUsedBytes = metrics.NewDesc(
+		containerLogFilesystemUsedBytesKey,
+		"Bytes used by the container's logs on the filesystem",
+		[]string{"namespace", "pod", "container"}, nil,
+		metrics.ALPHA, "",
+	)
+)
+
+// logMetricsCollector collects container log filesystem usage from a cached
+// kubelet stats summary.
+type logMetricsCollector struct {
+	metrics.BaseStableCollector
+
+	cache *statscache.Cache
+}
+
+// NewLogMetricsCollector creates a new container log filesystem usage
+// prometheus collector that reads from cache rather than scraping the
+// kubelet itself.
+func NewLogMetricsCollector(cache *statscache.Cache) metrics.StableCollector {
+	return &logMetricsCollector{cache: cache}
+}
+
+// DescribeWithStability implements the metrics.StableCollector interface.
+func (collector *logMetricsCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- containerLogFilesystemUsedBytes
+}
+
+// CollectWithStability implements the metrics.StableCollector interface.
+func (collector *logMetricsCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	statsSummary := collector.cache.Get()
+
+	for _, podStats := range statsSummary.Pods {
+		for _, containerStats := range podStats.Containers {
+			if containerStats.Logs == nil || containerStats.Logs.UsedBytes == nil {
+				continue
+			}
+			ch <- metrics.NewLazyConstMetric(
+				containerLogFilesystemUsedBytes, metrics.GaugeValue,
+				float64(*containerStats.Logs.UsedBytes),
+				podStats.PodRef.Namespace, podStats.PodRef.Name, containerStats.Name,
+			)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package collectors
+
+import (
+	"github.com/cofyc/kubelet-exporter/pkg/kubeinformer"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+)
+
+const (
+	boundPVCountKey    = "kube_pv_collector_bound_pv_count"
+	unboundPVCountKey  = "kube_pv_collector_unbound_pv_count"
+	boundPVCCountKey   = "kube_pv_collector_bound_pvc_count"
+	unboundPVCCountKey = "kube_pv_collector_unbound_pvc_count"
+)
+
+var (
+	boundPVCount = metrics.NewDesc(
+		boundPVCountKey,
+		"Number of bound PersistentVolumes",
+		[]string{"storage_class"}, nil,
+		metrics.ALPHA, "",
+	)
+	unboundPVCount = metrics.NewDesc(
+		unboundPVCountKey,
+		"Number of unbound PersistentVolumes",
+		[]string{"storage_class"}, nil,
+		metrics.ALPHA, "",
+	)
+	boundPVCCount = metrics.NewDesc(
+		boundPVCCountKey,
+		"Number of bound PersistentVolumeClaims",
+		[]string{"namespace"}, nil,
+		metrics.ALPHA, "",
+	)
+	unboundPVCCount = metrics.NewDesc(
+		unboundPVCCountKey,
+		"Number of unbound PersistentVolumeClaims",
+		[]string{"namespace"}, nil,
+		metrics.ALPHA, "",
+	)
+)
+
+// pvCountCollector reports cluster-wide bound/unbound PV and PVC counts,
+// analogous to the upstream kube-state-metrics PV collector, by iterating
+// the same PVC/PV informer caches volumeStatsCollector uses for enrichment.
+type pvCountCollector struct {
+	metrics.BaseStableCollector
+
+	pvcIndex *kubeinformer.PVCIndex
+}
+
+// NewPVCountCollector creates a new bound/unbound PV & PVC count prometheus
+// collector. It requires --enable-kube-api's informer index.
+func NewPVCountCollector(pvcIndex *kubeinformer.PVCIndex) metrics.StableCollector {
+	return &pvCountCollector{pvcIndex: pvcIndex}
+}
+
+// DescribeWithStability implements the metrics.StableCollector interface.
+func (collector *pvCountCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- boundPVCount
+	ch <- unboundPVCount
+	ch <- boundPVCCount
+	ch <- unboundPVCCount
+}
+
+// CollectWithStability implements the metrics.StableCollector interface.
+func (collector *pvCountCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	boundPVs := map[string]int{}
+	unboundPVs := map[string]int{}
+	for _, pv := range collector.pvcIndex.ListPVs() {
+		if pv.Status.Phase == v1.VolumeBound {
+			boundPVs[pv.Spec.StorageClassName]++
+		} else {
+			unboundPVs[pv.Spec.StorageClassName]++
+		}
+	}
+	for storageClass, count := range boundPVs {
+		ch <- metrics.NewLazyConstMetric(boundPVCount, metrics.GaugeValue, float64(count), storageClass)
+	}
+	for storageClass, count := range unboundPVs {
+		ch <- metrics.NewLazyConstMetric(unboundPVCount, metrics.GaugeValue, float64(count), storageClass)
+	}
+
+	boundPVCs := map[string]int{}
+	unboundPVCs := map[string]int{}
+	for _, pvc := range collector.pvcIndex.ListPVCs() {
+		if pvc.Status.Phase == v1.ClaimBound {
+			boundPVCs[pvc.Namespace]++
+		} else {
+			unboundPVCs[pvc.Namespace]++
+		}
+	}
+	for namespace, count := range boundPVCs {
+		ch <- metrics.NewLazyConstMetric(boundPVCCount, metrics.GaugeValue, float64(count), namespace)
+	}
+	for namespace, count := range unboundPVCs {
+		ch <- metrics.NewLazyConstMetric(unboundPVCCount, metrics.GaugeValue, float64(count), namespace)
+	}
+}
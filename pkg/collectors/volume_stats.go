@@ -1,16 +1,10 @@
 package collectors
 
 import (
-	"context"
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
-	"time"
-
-	"github.com/golang/glog"
-	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/net/context/ctxhttp"
+	"github.com/cofyc/kubelet-exporter/pkg/kubeinformer"
+	"github.com/cofyc/kubelet-exporter/pkg/statscache"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/component-base/metrics"
 	"k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 )
 
@@ -21,84 +15,103 @@ const (
 	volumeStatsInodesKey         = "kubelet_volume_stats_inodes"
 	volumeStatsInodesFreeKey     = "kubelet_volume_stats_inodes_free"
 	volumeStatsInodesUsedKey     = "kubelet_volume_stats_inodes_used"
+	volumeStatsPVCInfoKey        = "kubelet_volume_stats_pvc_info"
 )
 
 var (
-	volumeStatsCapacityBytes = prometheus.NewDesc(
+	// These mirror the identically-named metrics the upstream kubelet has
+	// shipped as STABLE since https://github.com/kubernetes/enhancements/issues/1206,
+	// so downstream consumers get the same lifecycle guarantees here.
+	volumeStatsCapacityBytes = metrics.NewDesc(
 		volumeStatsCapacityBytesKey,
 		"Capacity in bytes of the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
 	)
-	volumeStatsAvailableBytes = prometheus.NewDesc(
+	volumeStatsAvailableBytes = metrics.NewDesc(
 		volumeStatsAvailableBytesKey,
 		"Number of available bytes in the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
 	)
-	volumeStatsUsedBytes = prometheus.NewDesc(
+	volumeStatsUsedBytes = metrics.NewDesc(
 		volumeStatsUsedBytesKey,
 		"Number of used bytes in the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
 	)
-	volumeStatsInodes = prometheus.NewDesc(
+	volumeStatsInodes = metrics.NewDesc(
 		volumeStatsInodesKey,
 		"Maximum number of inodes in the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
 	)
-	volumeStatsInodesFree = prometheus.NewDesc(
+	volumeStatsInodesFree = metrics.NewDesc(
 		volumeStatsInodesFreeKey,
 		"Number of free inodes in the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
 	)
-	volumeStatsInodesUsed = prometheus.NewDesc(
+	volumeStatsInodesUsed = metrics.NewDesc(
 		volumeStatsInodesUsedKey,
 		"Number of used inodes in the volume",
 		[]string{"namespace", "persistentvolumeclaim"}, nil,
+		metrics.STABLE, "",
+	)
+	// volumeStatsPVCInfo carries PVC metadata that doesn't belong on the
+	// bytes/inodes gauges above, joinable on namespace/persistentvolumeclaim,
+	// following the kube-state-metrics *_info convention. It is new to this
+	// exporter, so it starts at ALPHA.
+	volumeStatsPVCInfo = metrics.NewDesc(
+		volumeStatsPVCInfoKey,
+		"Information about the PVC backing a volume",
+		[]string{"namespace", "persistentvolumeclaim", "storageclass", "persistentvolume", "node", "phase"}, nil,
+		metrics.ALPHA, "",
 	)
 )
 
-// volumeStatsCollector collects metrics from kubelet stats summary.
+// volumeStatsCollector collects metrics from a cached kubelet stats summary.
 type volumeStatsCollector struct {
-	host string
+	metrics.BaseStableCollector
+
+	cache    *statscache.Cache
+	pvcIndex *kubeinformer.PVCIndex
+	nodeName string
 }
 
-// NewVolumeStatsCollector creates a new volume stats prometheus collector.
-func NewVolumeStatsCollector(host string) prometheus.Collector {
-	return &volumeStatsCollector{host: host}
+// NewVolumeStatsCollector creates a new volume stats prometheus collector
+// that reads from cache rather than scraping the kubelet itself. pvcIndex
+// enables the --enable-kube-api feature: when non-nil, it is consulted to
+// emit kubelet_volume_stats_pvc_info for every collected PVC present in the
+// index, labelled with
+// storage class, bound PV, nodeName (the node this exporter runs on), and
+// phase. pvcIndex may be nil.
+func NewVolumeStatsCollector(cache *statscache.Cache, pvcIndex *kubeinformer.PVCIndex, nodeName string) metrics.StableCollector {
+	return &volumeStatsCollector{cache: cache, pvcIndex: pvcIndex, nodeName: nodeName}
 }
 
-// Describe implements the prometheus.Collector interface.
-func (collector *volumeStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+// DescribeWithStability implements the metrics.StableCollector interface.
+func (collector *volumeStatsCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
 	ch <- volumeStatsCapacityBytes
 	ch <- volumeStatsAvailableBytes
 	ch <- volumeStatsUsedBytes
 	ch <- volumeStatsInodes
 	ch <- volumeStatsInodesFree
 	ch <- volumeStatsInodesUsed
+	ch <- volumeStatsPVCInfo
 }
 
-// Collect implements the prometheus.Collector interface.
-func (collector *volumeStatsCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	resp, err := ctxhttp.Get(ctx, http.DefaultClient, collector.host)
-	if err != nil {
-		glog.Errorf("failed to get stats from %s: %v", collector.host, err)
-		return
-	}
-	defer resp.Body.Close()
-	rBody, _ := ioutil.ReadAll(resp.Body)
-
-	statsSummary := v1alpha1.Summary{}
-	err = json.Unmarshal(rBody, &statsSummary)
-	if err != nil {
-		glog.Errorf("failed to parse stats summary from %s: %v", collector.host, err)
-		return
-	}
+// CollectWithStability implements the metrics.StableCollector interface.
+func (collector *volumeStatsCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	statsSummary := collector.cache.Get()
 
-	addGauge := func(desc *prometheus.Desc, pvcRef *v1alpha1.PVCReference, v float64, lv ...string) {
+	addGauge := func(desc *metrics.Desc, pvcRef *v1alpha1.PVCReference, v *uint64, lv ...string) {
+		if v == nil {
+			// +optional: the kubelet may not have this stat yet, e.g. right after mount
+			return
+		}
 		lv = append([]string{pvcRef.Namespace, pvcRef.Name}, lv...)
-		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, lv...)
+		ch <- metrics.NewLazyConstMetric(desc, metrics.GaugeValue, float64(*v), lv...)
 	}
 
 	if statsSummary.Pods != nil {
@@ -118,14 +131,32 @@ func (collector *volumeStatsCollector) Collect(ch chan<- prometheus.Metric) {
 					// ignore if already collected
 					continue
 				}
-				addGauge(volumeStatsCapacityBytes, pvcRef, float64(*volumeStat.CapacityBytes))
-				addGauge(volumeStatsAvailableBytes, pvcRef, float64(*volumeStat.AvailableBytes))
-				addGauge(volumeStatsUsedBytes, pvcRef, float64(*volumeStat.UsedBytes))
-				addGauge(volumeStatsInodes, pvcRef, float64(*volumeStat.Inodes))
-				addGauge(volumeStatsInodesFree, pvcRef, float64(*volumeStat.InodesFree))
-				addGauge(volumeStatsInodesUsed, pvcRef, float64(*volumeStat.InodesUsed))
+				addGauge(volumeStatsCapacityBytes, pvcRef, volumeStat.CapacityBytes)
+				addGauge(volumeStatsAvailableBytes, pvcRef, volumeStat.AvailableBytes)
+				addGauge(volumeStatsUsedBytes, pvcRef, volumeStat.UsedBytes)
+				addGauge(volumeStatsInodes, pvcRef, volumeStat.Inodes)
+				addGauge(volumeStatsInodesFree, pvcRef, volumeStat.InodesFree)
+				addGauge(volumeStatsInodesUsed, pvcRef, volumeStat.InodesUsed)
+				collector.addPVCInfo(ch, pvcRef)
 				allPVCs.Insert(pvcUniqStr)
 			}
 		}
 	}
 }
+
+// addPVCInfo emits kubelet_volume_stats_pvc_info for pvcRef if the collector
+// was configured with a PVC index (--enable-kube-api) and the PVC is present
+// in it. It is a no-op otherwise.
+func (collector *volumeStatsCollector) addPVCInfo(ch chan<- metrics.Metric, pvcRef *v1alpha1.PVCReference) {
+	if collector.pvcIndex == nil {
+		return
+	}
+	info, ok := collector.pvcIndex.LookupPVC(pvcRef.Namespace, pvcRef.Name)
+	if !ok {
+		return
+	}
+	ch <- metrics.NewLazyConstMetric(
+		volumeStatsPVCInfo, metrics.GaugeValue, 1,
+		pvcRef.Namespace, pvcRef.Name, info.StorageClass, info.PersistentVolume, collector.nodeName, info.Phase,
+	)
+}
@@ -0,0 +1,87 @@
+// Package kubeinformer maintains informer-backed, in-memory indexes of
+// Kubernetes objects so collectors can enrich kubelet-reported metrics with
+// API metadata without hitting the API server on every scrape.
+package kubeinformer
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PVCInfo is the PersistentVolumeClaim metadata a PVCIndex can attach to the
+// volume metrics collected for that claim.
+type PVCInfo struct {
+	StorageClass     string
+	PersistentVolume string
+	Phase            string
+}
+
+// PVCIndex is an informer-backed index of PersistentVolumeClaims and
+// PersistentVolumes. PVCs are keyed by "namespace/name"; PVs, being
+// cluster-scoped, by "name".
+type PVCIndex struct {
+	factory     informers.SharedInformerFactory
+	pvcInformer cache.SharedIndexInformer
+	pvInformer  cache.SharedIndexInformer
+}
+
+// NewPVCIndex builds a PVCIndex backed by shared informers for
+// PersistentVolumeClaims and PersistentVolumes. Call Run to start them.
+func NewPVCIndex(client kubernetes.Interface) *PVCIndex {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	return &PVCIndex{
+		factory:     factory,
+		pvcInformer: factory.Core().V1().PersistentVolumeClaims().Informer(),
+		pvInformer:  factory.Core().V1().PersistentVolumes().Informer(),
+	}
+}
+
+// Run starts the underlying informers and blocks until their caches have
+// synced or stopCh is closed.
+func (idx *PVCIndex) Run(stopCh <-chan struct{}) error {
+	idx.factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, idx.pvcInformer.HasSynced, idx.pvInformer.HasSynced) {
+		return fmt.Errorf("failed to sync PVC/PV informer caches")
+	}
+	return nil
+}
+
+// LookupPVC returns the PVCInfo for the PVC identified by namespace/name, or
+// ok=false if it isn't (yet) present in the index.
+func (idx *PVCIndex) LookupPVC(namespace, name string) (info PVCInfo, ok bool) {
+	obj, exists, err := idx.pvcInformer.GetStore().GetByKey(namespace + "/" + name)
+	if err != nil || !exists {
+		return PVCInfo{}, false
+	}
+	pvc := obj.(*v1.PersistentVolumeClaim)
+	info.Phase = string(pvc.Status.Phase)
+	info.PersistentVolume = pvc.Spec.VolumeName
+	if pvc.Spec.StorageClassName != nil {
+		info.StorageClass = *pvc.Spec.StorageClassName
+	}
+	return info, true
+}
+
+// ListPVCs returns every PersistentVolumeClaim currently in the index.
+func (idx *PVCIndex) ListPVCs() []*v1.PersistentVolumeClaim {
+	objs := idx.pvcInformer.GetStore().List()
+	pvcs := make([]*v1.PersistentVolumeClaim, 0, len(objs))
+	for _, obj := range objs {
+		pvcs = append(pvcs, obj.(*v1.PersistentVolumeClaim))
+	}
+	return pvcs
+}
+
+// ListPVs returns every PersistentVolume currently in the index.
+func (idx *PVCIndex) ListPVs() []*v1.PersistentVolume {
+	objs := idx.pvInformer.GetStore().List()
+	pvs := make([]*v1.PersistentVolume, 0, len(objs))
+	for _, obj := range objs {
+		pvs = append(pvs, obj.(*v1.PersistentVolume))
+	}
+	return pvs
+}
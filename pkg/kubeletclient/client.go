@@ -0,0 +1,161 @@
+// Package kubeletclient builds HTTP clients for talking to the kubelet's
+// read-only and secure (HTTPS) endpoints, and resolves the address of the
+// node the exporter is running on.
+package kubeletclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// tokenReloadInterval is how often a configured bearer-token file is re-read
+// from disk, so that rotated service account tokens are picked up without
+// restarting the exporter.
+const tokenReloadInterval = 1 * time.Minute
+
+// Config describes how to build an *http.Client for scraping the kubelet.
+type Config struct {
+	// CAFile is the PEM-encoded CA bundle used to verify the kubelet's
+	// serving certificate. Empty uses the system cert pool.
+	CAFile string
+	// ClientCertFile and ClientKeyFile, if both set, are presented to the
+	// kubelet as a client certificate.
+	ClientCertFile string
+	ClientKeyFile  string
+	// TokenFile, if set, is read and sent as an "Authorization: Bearer"
+	// header on every request. The file is reloaded periodically so token
+	// rotation (e.g. a projected service account token) is picked up.
+	TokenFile string
+	// InsecureSkipVerify disables verification of the kubelet's serving
+	// certificate. Only useful for testing.
+	InsecureSkipVerify bool
+}
+
+// NewClient builds an *http.Client configured according to cfg. Callers that
+// don't need TLS or token auth (cfg is the zero value) get back a client with
+// a plain http.Transport and default TLS settings. Unlike http.DefaultClient,
+// its transport does not use proxy settings from the environment and sets no
+// dial or TLS handshake timeouts.
+func NewClient(cfg Config) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		caCert, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubelet CA file %s: %v", cfg.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in kubelet CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" && cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubelet client cert/key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.RoundTripper(&http.Transport{TLSClientConfig: tlsConfig})
+
+	if cfg.TokenFile != "" {
+		bt, err := newBearerTokenTransport(cfg.TokenFile, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = bt
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// bearerTokenTransport injects an "Authorization: Bearer <token>" header
+// read from a file, reloading it periodically in the background.
+type bearerTokenTransport struct {
+	base  http.RoundTripper
+	token atomic.Value // string
+}
+
+func newBearerTokenTransport(tokenFile string, base http.RoundTripper) (*bearerTokenTransport, error) {
+	t := &bearerTokenTransport{base: base}
+	if err := t.reload(tokenFile); err != nil {
+		return nil, err
+	}
+	go t.reloadLoop(tokenFile)
+	return t, nil
+}
+
+func (t *bearerTokenTransport) reload(tokenFile string) error {
+	b, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read kubelet token file %s: %v", tokenFile, err)
+	}
+	t.token.Store(string(b))
+	return nil
+}
+
+func (t *bearerTokenTransport) reloadLoop(tokenFile string) {
+	for range time.Tick(tokenReloadInterval) {
+		if err := t.reload(tokenFile); err != nil {
+			glog.Errorf("failed to reload kubelet token file %s: %v", tokenFile, err)
+		}
+	}
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if token, ok := t.token.Load().(string); ok && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// InClusterClientset builds a Kubernetes clientset from the pod's in-cluster
+// service account, for features that need to talk to the API server
+// (node address discovery, the PVC/PV informer index).
+func InClusterClientset() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Kubernetes client: %v", err)
+	}
+	return clientset, nil
+}
+
+// DiscoverNodeAddress looks up the InternalIP of the named node via the
+// in-cluster Kubernetes API, for DaemonSet deployments that only know their
+// node's name (from the downward API's NODE_NAME) and need the node's IP to
+// reach its kubelet directly.
+func DiscoverNodeAddress(nodeName string) (string, error) {
+	clientset, err := InClusterClientset()
+	if err != nil {
+		return "", err
+	}
+	node, err := clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get node %s: %v", nodeName, err)
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == v1.NodeInternalIP {
+			return addr.Address, nil
+		}
+	}
+	return "", fmt.Errorf("node %s has no InternalIP address", nodeName)
+}
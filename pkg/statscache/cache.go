@@ -0,0 +1,114 @@
+// Package statscache polls a kubelet's /stats/summary endpoint in the
+// background and caches the most recent response, so that Prometheus
+// scrapes never block on a synchronous kubelet request and overlapping
+// Prometheus scrapes never trigger overlapping kubelet requests.
+package statscache
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context/ctxhttp"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+var (
+	scrapeDuration = metrics.NewHistogram(&metrics.HistogramOpts{
+		Name:           "kubelet_exporter_scrape_duration_seconds",
+		Help:           "Duration in seconds of each successful background scrape of the kubelet's /stats/summary endpoint",
+		Buckets:        metrics.DefBuckets,
+		StabilityLevel: metrics.ALPHA,
+	})
+	scrapeErrorsTotal = metrics.NewCounter(&metrics.CounterOpts{
+		Name:           "kubelet_exporter_scrape_errors_total",
+		Help:           "Number of failed background scrapes of the kubelet's /stats/summary endpoint",
+		StabilityLevel: metrics.ALPHA,
+	})
+	lastScrapeTimestamp = metrics.NewGauge(&metrics.GaugeOpts{
+		Name:           "kubelet_exporter_last_scrape_timestamp_seconds",
+		Help:           "Unix timestamp of the last successful background scrape of the kubelet's /stats/summary endpoint",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(scrapeDuration)
+	legacyregistry.MustRegister(scrapeErrorsTotal)
+	legacyregistry.MustRegister(lastScrapeTimestamp)
+}
+
+// Cache holds the most recently scraped stats summary for a single kubelet.
+type Cache struct {
+	host     string
+	client   *http.Client
+	interval time.Duration
+
+	summary atomic.Value // v1alpha1.Summary
+}
+
+// New creates a Cache that will scrape host through client every interval
+// once Run is called.
+func New(host string, client *http.Client, interval time.Duration) *Cache {
+	return &Cache{host: host, client: client, interval: interval}
+}
+
+// Run performs an initial scrape, then scrapes host every interval until
+// stopCh is closed. It blocks until the initial scrape completes so that Get
+// can be relied on immediately after Run returns.
+func (c *Cache) Run(stopCh <-chan struct{}) {
+	c.scrapeOnce()
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.scrapeOnce()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Get returns the most recently cached stats summary, or the zero value if
+// no scrape has completed yet.
+func (c *Cache) Get() v1alpha1.Summary {
+	if s, ok := c.summary.Load().(v1alpha1.Summary); ok {
+		return s
+	}
+	return v1alpha1.Summary{}
+}
+
+func (c *Cache) scrapeOnce() {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	resp, err := ctxhttp.Get(ctx, c.client, c.host)
+	if err != nil {
+		glog.Errorf("failed to get stats from %s: %v", c.host, err)
+		scrapeErrorsTotal.Inc()
+		return
+	}
+	defer resp.Body.Close()
+	rBody, _ := ioutil.ReadAll(resp.Body)
+
+	summary := v1alpha1.Summary{}
+	if err := json.Unmarshal(rBody, &summary); err != nil {
+		glog.Errorf("failed to parse stats summary from %s: %v", c.host, err)
+		scrapeErrorsTotal.Inc()
+		return
+	}
+
+	c.summary.Store(summary)
+	scrapeDuration.Observe(time.Since(start).Seconds())
+	lastScrapeTimestamp.Set(float64(time.Now().Unix()))
+}